@@ -0,0 +1,271 @@
+// Package postgres is a database.ConversationStore backed by PostgreSQL,
+// intended for multi-device setups where several siggo instances share
+// one conversation history.
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/derricw/siggo/database"
+
+	_ "github.com/lib/pq"
+)
+
+var migrations = []database.Migration{
+	{
+		Version: 1,
+		SQL: `
+			CREATE TABLE IF NOT EXISTS contacts (
+				number TEXT PRIMARY KEY,
+				name TEXT NOT NULL DEFAULT ''
+			);
+			CREATE TABLE IF NOT EXISTS conversations (
+				party_key TEXT PRIMARY KEY
+			);
+			CREATE TABLE IF NOT EXISTS messages (
+				conversation_key TEXT NOT NULL,
+				timestamp BIGINT NOT NULL,
+				content TEXT NOT NULL,
+				from_name TEXT NOT NULL,
+				is_delivered BOOLEAN NOT NULL DEFAULT FALSE,
+				is_read BOOLEAN NOT NULL DEFAULT FALSE,
+				PRIMARY KEY (conversation_key, timestamp)
+			);
+			CREATE TABLE IF NOT EXISTS attachments (
+				conversation_key TEXT NOT NULL,
+				timestamp BIGINT NOT NULL,
+				id TEXT NOT NULL,
+				content_type TEXT NOT NULL DEFAULT '',
+				filename TEXT NOT NULL DEFAULT '',
+				size BIGINT NOT NULL DEFAULT 0,
+				local_path TEXT NOT NULL DEFAULT '',
+				PRIMARY KEY (conversation_key, timestamp, id),
+				FOREIGN KEY (conversation_key, timestamp) REFERENCES messages (conversation_key, timestamp)
+			);
+		`,
+	},
+}
+
+// Store is a database.ConversationStore backed by PostgreSQL.
+type Store struct {
+	db *sql.DB
+}
+
+// New opens a connection pool to a PostgreSQL database using dsn.
+// Callers must call Open before using the store.
+func New(dsn string) (*Store, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("opening postgres database: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+// Open runs any pending migrations.
+func (s *Store) Open() error {
+	if _, err := s.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`); err != nil {
+		return fmt.Errorf("bootstrapping schema_migrations: %w", err)
+	}
+	for _, m := range migrations {
+		var applied bool
+		err := s.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM schema_migrations WHERE version = $1)`, m.Version).Scan(&applied)
+		if err != nil {
+			return fmt.Errorf("checking migration %d: %w", m.Version, err)
+		}
+		if applied {
+			continue
+		}
+		tx, err := s.db.Begin()
+		if err != nil {
+			return fmt.Errorf("beginning migration %d: %w", m.Version, err)
+		}
+		if _, err := tx.Exec(m.SQL); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("applying migration %d: %w", m.Version, err)
+		}
+		if _, err := tx.Exec(`INSERT INTO schema_migrations (version) VALUES ($1)`, m.Version); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("recording migration %d: %w", m.Version, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("committing migration %d: %w", m.Version, err)
+		}
+	}
+	return nil
+}
+
+// Close closes the underlying connection pool.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Contacts returns every known contact.
+func (s *Store) Contacts() ([]*database.Contact, error) {
+	rows, err := s.db.Query(`SELECT number, name FROM contacts`)
+	if err != nil {
+		return nil, fmt.Errorf("querying contacts: %w", err)
+	}
+	defer rows.Close()
+
+	var out []*database.Contact
+	for rows.Next() {
+		c := &database.Contact{}
+		if err := rows.Scan(&c.Number, &c.Name); err != nil {
+			return nil, fmt.Errorf("scanning contact: %w", err)
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+// SaveContact inserts or updates a contact.
+func (s *Store) SaveContact(c *database.Contact) error {
+	_, err := s.db.Exec(`
+		INSERT INTO contacts (number, name) VALUES ($1, $2)
+		ON CONFLICT (number) DO UPDATE SET name = excluded.name
+	`, c.Number, c.Name)
+	if err != nil {
+		return fmt.Errorf("saving contact %s: %w", c.Number, err)
+	}
+	return nil
+}
+
+// Conversations returns every known conversation.
+func (s *Store) Conversations() ([]*database.Conversation, error) {
+	rows, err := s.db.Query(`SELECT party_key FROM conversations`)
+	if err != nil {
+		return nil, fmt.Errorf("querying conversations: %w", err)
+	}
+	defer rows.Close()
+
+	var out []*database.Conversation
+	for rows.Next() {
+		c := &database.Conversation{}
+		if err := rows.Scan(&c.PartyKey); err != nil {
+			return nil, fmt.Errorf("scanning conversation: %w", err)
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+// SaveConversation inserts or updates a conversation.
+func (s *Store) SaveConversation(c *database.Conversation) error {
+	_, err := s.db.Exec(`INSERT INTO conversations (party_key) VALUES ($1) ON CONFLICT (party_key) DO NOTHING`, c.PartyKey)
+	if err != nil {
+		return fmt.Errorf("saving conversation %s: %w", c.PartyKey, err)
+	}
+	return nil
+}
+
+// Messages returns every message belonging to a conversation, ordered by
+// timestamp.
+func (s *Store) Messages(conversationKey string) ([]*database.Message, error) {
+	rows, err := s.db.Query(`
+		SELECT conversation_key, timestamp, content, from_name, is_delivered, is_read
+		FROM messages WHERE conversation_key = $1 ORDER BY timestamp ASC
+	`, conversationKey)
+	if err != nil {
+		return nil, fmt.Errorf("querying messages for %s: %w", conversationKey, err)
+	}
+	defer rows.Close()
+
+	var out []*database.Message
+	for rows.Next() {
+		m := &database.Message{}
+		if err := rows.Scan(&m.ConversationKey, &m.Timestamp, &m.Content, &m.From, &m.IsDelivered, &m.IsRead); err != nil {
+			return nil, fmt.Errorf("scanning message: %w", err)
+		}
+		out = append(out, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, m := range out {
+		attachments, err := s.attachments(m.ConversationKey, m.Timestamp)
+		if err != nil {
+			return nil, fmt.Errorf("loading attachments for message %d in %s: %w", m.Timestamp, m.ConversationKey, err)
+		}
+		m.Attachments = attachments
+	}
+	return out, nil
+}
+
+// attachments returns every attachment belonging to a single message.
+func (s *Store) attachments(conversationKey string, timestamp int64) ([]database.Attachment, error) {
+	rows, err := s.db.Query(`
+		SELECT id, content_type, filename, size, local_path
+		FROM attachments WHERE conversation_key = $1 AND timestamp = $2
+	`, conversationKey, timestamp)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []database.Attachment
+	for rows.Next() {
+		a := database.Attachment{}
+		if err := rows.Scan(&a.ID, &a.ContentType, &a.Filename, &a.Size, &a.LocalPath); err != nil {
+			return nil, fmt.Errorf("scanning attachment: %w", err)
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+// SaveMessage inserts or updates a message and its attachments.
+func (s *Store) SaveMessage(m *database.Message) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("beginning save of message %d in %s: %w", m.Timestamp, m.ConversationKey, err)
+	}
+
+	if _, err := tx.Exec(`
+		INSERT INTO messages (conversation_key, timestamp, content, from_name, is_delivered, is_read)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (conversation_key, timestamp) DO UPDATE SET
+			content = excluded.content,
+			from_name = excluded.from_name,
+			is_delivered = excluded.is_delivered,
+			is_read = excluded.is_read
+	`, m.ConversationKey, m.Timestamp, m.Content, m.From, m.IsDelivered, m.IsRead); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("saving message %d in %s: %w", m.Timestamp, m.ConversationKey, err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM attachments WHERE conversation_key = $1 AND timestamp = $2`, m.ConversationKey, m.Timestamp); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("clearing attachments for message %d in %s: %w", m.Timestamp, m.ConversationKey, err)
+	}
+	for _, a := range m.Attachments {
+		if _, err := tx.Exec(`
+			INSERT INTO attachments (conversation_key, timestamp, id, content_type, filename, size, local_path)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+		`, m.ConversationKey, m.Timestamp, a.ID, a.ContentType, a.Filename, a.Size, a.LocalPath); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("saving attachment %s for message %d in %s: %w", a.ID, m.Timestamp, m.ConversationKey, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing message %d in %s: %w", m.Timestamp, m.ConversationKey, err)
+	}
+	return nil
+}
+
+// SetReceipt updates the delivery/read state of a message.
+func (s *Store) SetReceipt(conversationKey string, timestamp int64, isDelivered, isRead bool) error {
+	_, err := s.db.Exec(`
+		UPDATE messages SET is_delivered = $1, is_read = $2
+		WHERE conversation_key = $3 AND timestamp = $4
+	`, isDelivered, isRead, conversationKey, timestamp)
+	if err != nil {
+		return fmt.Errorf("setting receipt for %d in %s: %w", timestamp, conversationKey, err)
+	}
+	return nil
+}
@@ -0,0 +1,275 @@
+// Package sqlite is a database.ConversationStore backed by a local
+// SQLite file, intended as siggo's default store for single-device use.
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/derricw/siggo/database"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+var migrations = []database.Migration{
+	{
+		Version: 1,
+		SQL: `
+			CREATE TABLE contacts (
+				number TEXT PRIMARY KEY,
+				name TEXT NOT NULL DEFAULT ''
+			);
+			CREATE TABLE conversations (
+				party_key TEXT PRIMARY KEY
+			);
+			CREATE TABLE messages (
+				conversation_key TEXT NOT NULL,
+				timestamp INTEGER NOT NULL,
+				content TEXT NOT NULL,
+				from_name TEXT NOT NULL,
+				is_delivered BOOLEAN NOT NULL DEFAULT 0,
+				is_read BOOLEAN NOT NULL DEFAULT 0,
+				PRIMARY KEY (conversation_key, timestamp)
+			);
+		`,
+	},
+	{
+		Version: 2,
+		SQL: `
+			CREATE TABLE attachments (
+				conversation_key TEXT NOT NULL,
+				timestamp INTEGER NOT NULL,
+				id TEXT NOT NULL,
+				content_type TEXT NOT NULL DEFAULT '',
+				filename TEXT NOT NULL DEFAULT '',
+				size INTEGER NOT NULL DEFAULT 0,
+				local_path TEXT NOT NULL DEFAULT '',
+				PRIMARY KEY (conversation_key, timestamp, id),
+				FOREIGN KEY (conversation_key, timestamp) REFERENCES messages (conversation_key, timestamp)
+			);
+		`,
+	},
+}
+
+// Store is a database.ConversationStore backed by SQLite.
+type Store struct {
+	db *sql.DB
+}
+
+// New opens a SQLite database at path, creating it if it doesn't exist.
+// Callers must call Open before using the store.
+func New(path string) (*Store, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, fmt.Errorf("opening sqlite database: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+// Open runs any pending migrations.
+func (s *Store) Open() error {
+	if _, err := s.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`); err != nil {
+		return fmt.Errorf("bootstrapping schema_migrations: %w", err)
+	}
+	for _, m := range migrations {
+		var applied bool
+		err := s.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM schema_migrations WHERE version = ?)`, m.Version).Scan(&applied)
+		if err != nil {
+			return fmt.Errorf("checking migration %d: %w", m.Version, err)
+		}
+		if applied {
+			continue
+		}
+		tx, err := s.db.Begin()
+		if err != nil {
+			return fmt.Errorf("beginning migration %d: %w", m.Version, err)
+		}
+		if _, err := tx.Exec(m.SQL); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("applying migration %d: %w", m.Version, err)
+		}
+		if _, err := tx.Exec(`INSERT INTO schema_migrations (version) VALUES (?)`, m.Version); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("recording migration %d: %w", m.Version, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("committing migration %d: %w", m.Version, err)
+		}
+	}
+	return nil
+}
+
+// Close closes the underlying database handle.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Contacts returns every known contact.
+func (s *Store) Contacts() ([]*database.Contact, error) {
+	rows, err := s.db.Query(`SELECT number, name FROM contacts`)
+	if err != nil {
+		return nil, fmt.Errorf("querying contacts: %w", err)
+	}
+	defer rows.Close()
+
+	var out []*database.Contact
+	for rows.Next() {
+		c := &database.Contact{}
+		if err := rows.Scan(&c.Number, &c.Name); err != nil {
+			return nil, fmt.Errorf("scanning contact: %w", err)
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+// SaveContact inserts or updates a contact.
+func (s *Store) SaveContact(c *database.Contact) error {
+	_, err := s.db.Exec(`
+		INSERT INTO contacts (number, name) VALUES (?, ?)
+		ON CONFLICT (number) DO UPDATE SET name = excluded.name
+	`, c.Number, c.Name)
+	if err != nil {
+		return fmt.Errorf("saving contact %s: %w", c.Number, err)
+	}
+	return nil
+}
+
+// Conversations returns every known conversation.
+func (s *Store) Conversations() ([]*database.Conversation, error) {
+	rows, err := s.db.Query(`SELECT party_key FROM conversations`)
+	if err != nil {
+		return nil, fmt.Errorf("querying conversations: %w", err)
+	}
+	defer rows.Close()
+
+	var out []*database.Conversation
+	for rows.Next() {
+		c := &database.Conversation{}
+		if err := rows.Scan(&c.PartyKey); err != nil {
+			return nil, fmt.Errorf("scanning conversation: %w", err)
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+// SaveConversation inserts or updates a conversation.
+func (s *Store) SaveConversation(c *database.Conversation) error {
+	_, err := s.db.Exec(`INSERT INTO conversations (party_key) VALUES (?) ON CONFLICT (party_key) DO NOTHING`, c.PartyKey)
+	if err != nil {
+		return fmt.Errorf("saving conversation %s: %w", c.PartyKey, err)
+	}
+	return nil
+}
+
+// Messages returns every message belonging to a conversation, ordered by
+// timestamp.
+func (s *Store) Messages(conversationKey string) ([]*database.Message, error) {
+	rows, err := s.db.Query(`
+		SELECT conversation_key, timestamp, content, from_name, is_delivered, is_read
+		FROM messages WHERE conversation_key = ? ORDER BY timestamp ASC
+	`, conversationKey)
+	if err != nil {
+		return nil, fmt.Errorf("querying messages for %s: %w", conversationKey, err)
+	}
+	defer rows.Close()
+
+	var out []*database.Message
+	for rows.Next() {
+		m := &database.Message{}
+		if err := rows.Scan(&m.ConversationKey, &m.Timestamp, &m.Content, &m.From, &m.IsDelivered, &m.IsRead); err != nil {
+			return nil, fmt.Errorf("scanning message: %w", err)
+		}
+		out = append(out, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, m := range out {
+		attachments, err := s.attachments(m.ConversationKey, m.Timestamp)
+		if err != nil {
+			return nil, fmt.Errorf("loading attachments for message %d in %s: %w", m.Timestamp, m.ConversationKey, err)
+		}
+		m.Attachments = attachments
+	}
+	return out, nil
+}
+
+// attachments returns every attachment belonging to a single message.
+func (s *Store) attachments(conversationKey string, timestamp int64) ([]database.Attachment, error) {
+	rows, err := s.db.Query(`
+		SELECT id, content_type, filename, size, local_path
+		FROM attachments WHERE conversation_key = ? AND timestamp = ?
+	`, conversationKey, timestamp)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []database.Attachment
+	for rows.Next() {
+		a := database.Attachment{}
+		if err := rows.Scan(&a.ID, &a.ContentType, &a.Filename, &a.Size, &a.LocalPath); err != nil {
+			return nil, fmt.Errorf("scanning attachment: %w", err)
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+// SaveMessage inserts or updates a message and its attachments.
+func (s *Store) SaveMessage(m *database.Message) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("beginning save of message %d in %s: %w", m.Timestamp, m.ConversationKey, err)
+	}
+
+	if _, err := tx.Exec(`
+		INSERT INTO messages (conversation_key, timestamp, content, from_name, is_delivered, is_read)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (conversation_key, timestamp) DO UPDATE SET
+			content = excluded.content,
+			from_name = excluded.from_name,
+			is_delivered = excluded.is_delivered,
+			is_read = excluded.is_read
+	`, m.ConversationKey, m.Timestamp, m.Content, m.From, m.IsDelivered, m.IsRead); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("saving message %d in %s: %w", m.Timestamp, m.ConversationKey, err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM attachments WHERE conversation_key = ? AND timestamp = ?`, m.ConversationKey, m.Timestamp); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("clearing attachments for message %d in %s: %w", m.Timestamp, m.ConversationKey, err)
+	}
+	for _, a := range m.Attachments {
+		if _, err := tx.Exec(`
+			INSERT INTO attachments (conversation_key, timestamp, id, content_type, filename, size, local_path)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, m.ConversationKey, m.Timestamp, a.ID, a.ContentType, a.Filename, a.Size, a.LocalPath); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("saving attachment %s for message %d in %s: %w", a.ID, m.Timestamp, m.ConversationKey, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing message %d in %s: %w", m.Timestamp, m.ConversationKey, err)
+	}
+	return nil
+}
+
+// SetReceipt updates the delivery/read state of a message.
+func (s *Store) SetReceipt(conversationKey string, timestamp int64, isDelivered, isRead bool) error {
+	_, err := s.db.Exec(`
+		UPDATE messages SET is_delivered = ?, is_read = ?
+		WHERE conversation_key = ? AND timestamp = ?
+	`, isDelivered, isRead, conversationKey, timestamp)
+	if err != nil {
+		return fmt.Errorf("setting receipt for %d in %s: %w", timestamp, conversationKey, err)
+	}
+	return nil
+}
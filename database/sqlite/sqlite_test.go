@@ -0,0 +1,33 @@
+package sqlite
+
+import (
+	"testing"
+
+	"github.com/derricw/siggo/database"
+)
+
+func TestOpenIsIdempotent(t *testing.T) {
+	s, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer s.Close()
+
+	if err := s.Open(); err != nil {
+		t.Fatalf("first Open: %v", err)
+	}
+	if err := s.Open(); err != nil {
+		t.Fatalf("second Open (re-running migrations): %v", err)
+	}
+
+	if err := s.SaveContact(&database.Contact{Number: "+15555550100", Name: "Alice"}); err != nil {
+		t.Fatalf("SaveContact: %v", err)
+	}
+	contacts, err := s.Contacts()
+	if err != nil {
+		t.Fatalf("Contacts: %v", err)
+	}
+	if len(contacts) != 1 || contacts[0].Name != "Alice" {
+		t.Fatalf("got %+v, want one contact named Alice", contacts)
+	}
+}
@@ -0,0 +1,79 @@
+// Package database provides persistent storage for siggo's contacts,
+// conversations, messages and delivery/read state, mirroring the store
+// pattern used by soju: a narrow interface with one implementation per
+// backend, each responsible for its own schema migrations.
+package database
+
+// Contact is the persisted representation of a contact.
+type Contact struct {
+	Number string
+	Name   string
+}
+
+// Conversation is the persisted representation of a conversation, keyed
+// by the party (contact or group) it belongs to.
+type Conversation struct {
+	PartyKey string
+}
+
+// Attachment is the persisted representation of a file attached to a
+// message. LocalPath points at where the cached copy lives on disk, so
+// it can still be found and opened after a restart.
+type Attachment struct {
+	ID          string
+	ContentType string
+	Filename    string
+	Size        int64
+	LocalPath   string
+}
+
+// Message is the persisted representation of a single message.
+type Message struct {
+	ConversationKey string
+	Content         string
+	From            string
+	Timestamp       int64
+	IsDelivered     bool
+	IsRead          bool
+	Attachments     []Attachment
+}
+
+// ConversationStore persists contacts, conversations and messages, and
+// tracks delivery/read transitions for each message. Implementations
+// must be safe for concurrent use.
+type ConversationStore interface {
+	// Open runs any pending migrations and prepares the store for use.
+	Open() error
+
+	// Close releases any resources held by the store.
+	Close() error
+
+	// Contacts returns every known contact.
+	Contacts() ([]*Contact, error)
+
+	// SaveContact inserts or updates a contact.
+	SaveContact(c *Contact) error
+
+	// Conversations returns every known conversation.
+	Conversations() ([]*Conversation, error)
+
+	// SaveConversation inserts or updates a conversation.
+	SaveConversation(c *Conversation) error
+
+	// Messages returns every message belonging to a conversation, ordered
+	// by timestamp.
+	Messages(conversationKey string) ([]*Message, error)
+
+	// SaveMessage inserts or updates a message.
+	SaveMessage(m *Message) error
+
+	// SetReceipt updates the delivery/read state of a message.
+	SetReceipt(conversationKey string, timestamp int64, isDelivered, isRead bool) error
+}
+
+// Migration is a single schema migration, applied in ascending Version
+// order by a store's Open method.
+type Migration struct {
+	Version int
+	SQL     string
+}
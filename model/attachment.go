@@ -0,0 +1,74 @@
+package model
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/derricw/siggo/signal"
+)
+
+// Attachment is a file sent or received alongside a message: an image,
+// document, or voice note.
+type Attachment struct {
+	ID          string
+	ContentType string
+	Filename    string
+	Size        int64
+	LocalPath   string
+}
+
+// cacheAttachment copies an attachment out of signal-cli's attachments
+// directory (where it's named by ID) into siggo's own cache, and
+// returns the Attachment recording where it landed.
+func (s *Siggo) cacheAttachment(a *signal.Attachment) (Attachment, error) {
+	src := filepath.Join(s.config.SignalAttachmentsDir, a.ID)
+	dstDir := s.config.AttachmentCacheDir
+	if err := os.MkdirAll(dstDir, 0o755); err != nil {
+		return Attachment{}, fmt.Errorf("creating attachment cache dir: %w", err)
+	}
+	// Filename comes verbatim from the remote peer's DataMessage, so it
+	// must never be trusted as a path - strip it down to a bare file
+	// name before it touches the filesystem.
+	filename := filepath.Base(a.Filename)
+	if filename == "" || filename == "." || filename == string(filepath.Separator) {
+		filename = a.ID
+	}
+	dst := filepath.Join(dstDir, a.ID+"-"+filename)
+
+	in, err := os.Open(src)
+	if err != nil {
+		return Attachment{}, fmt.Errorf("opening attachment %s: %w", a.ID, err)
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return Attachment{}, fmt.Errorf("creating cached attachment %s: %w", dst, err)
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, in); err != nil {
+		return Attachment{}, fmt.Errorf("copying attachment %s: %w", a.ID, err)
+	}
+
+	return Attachment{
+		ID:          a.ID,
+		ContentType: a.ContentType,
+		Filename:    a.Filename,
+		Size:        a.Size,
+		LocalPath:   dst,
+	}, nil
+}
+
+// OpenAttachment opens an attachment's cached file in the configured
+// viewer (AttachmentViewer, defaulting to xdg-open).
+func (s *Siggo) OpenAttachment(a *Attachment) error {
+	viewer := s.config.AttachmentViewer
+	if viewer == "" {
+		viewer = "xdg-open"
+	}
+	return exec.Command(viewer, a.LocalPath).Start()
+}
@@ -0,0 +1,39 @@
+package model
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/derricw/siggo/signal"
+)
+
+func TestCacheAttachmentSanitizesFilename(t *testing.T) {
+	attachmentsDir := t.TempDir()
+	cacheDir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(attachmentsDir, "att-1"), []byte("data"), 0o644); err != nil {
+		t.Fatalf("writing fake signal-cli attachment: %v", err)
+	}
+
+	s := &Siggo{config: &Config{
+		SignalAttachmentsDir: attachmentsDir,
+		AttachmentCacheDir:   cacheDir,
+	}}
+
+	cached, err := s.cacheAttachment(&signal.Attachment{
+		ID:       "att-1",
+		Filename: "../../../../../tmp/pwned.sh",
+	})
+	if err != nil {
+		t.Fatalf("cacheAttachment: %v", err)
+	}
+
+	if !strings.HasPrefix(cached.LocalPath, cacheDir) {
+		t.Fatalf("attachment escaped cache dir: got LocalPath %q, want it under %q", cached.LocalPath, cacheDir)
+	}
+	if filepath.Dir(cached.LocalPath) != cacheDir {
+		t.Fatalf("attachment landed in wrong directory: %q", cached.LocalPath)
+	}
+}
@@ -0,0 +1,61 @@
+package model
+
+import "strings"
+
+const groupKeyPrefix = "group:"
+
+// groupID extracts the group ID from a key produced by Group.Key, or
+// reports ok=false if key doesn't belong to a group.
+func groupID(key string) (string, bool) {
+	if !strings.HasPrefix(key, groupKeyPrefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(key, groupKeyPrefix), true
+}
+
+// Party is whoever a Conversation is with: a single Contact or a Group.
+// Conversations are keyed by Party.Key() rather than by Contact directly
+// so the same map can hold both.
+type Party interface {
+	Key() string
+	DisplayName() string
+}
+
+// Group is a Signal group conversation.
+type Group struct {
+	ID      string
+	Name    string
+	Members []*Contact
+}
+
+// NewGroup creates a Group with no members yet.
+func NewGroup(id, name string) *Group {
+	return &Group{
+		ID:      id,
+		Name:    name,
+		Members: make([]*Contact, 0),
+	}
+}
+
+// Key identifies the group independently of contact numbers, since a
+// group ID and a phone number could otherwise collide.
+func (g *Group) Key() string {
+	return "group:" + g.ID
+}
+
+func (g *Group) DisplayName() string {
+	if g.Name != "" {
+		return g.Name
+	}
+	return g.ID
+}
+
+// AddMember adds c to the group if it isn't already a member.
+func (g *Group) AddMember(c *Contact) {
+	for _, m := range g.Members {
+		if m.Number == c.Number {
+			return
+		}
+	}
+	g.Members = append(g.Members, c)
+}
@@ -1,12 +1,21 @@
 package model
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"sync"
+	"time"
 
+	"github.com/derricw/siggo/database"
 	"github.com/derricw/siggo/signal"
 )
 
+// TypingExpiry is how long a conversation keeps showing "is typing"
+// after the last typing notification, in case a "stopped" notification
+// never arrives.
+const TypingExpiry = 15 * time.Second
+
 var DeliveryStatus map[bool]string = map[bool]string{
 	true:  "<",
 	false: "?",
@@ -18,8 +27,19 @@ var ReadStatus map[bool]string = map[bool]string{
 }
 
 type Config struct {
-	UserName   string
-	UserNumber string
+	UserName     string
+	UserNumber   string
+	AutoMarkRead bool
+
+	// SignalAttachmentsDir is where signal-cli stores received
+	// attachments on disk, named by attachment ID.
+	SignalAttachmentsDir string
+	// AttachmentCacheDir is where siggo copies attachments it wants to
+	// keep around, e.g. to hand off to AttachmentViewer.
+	AttachmentCacheDir string
+	// AttachmentViewer is the command used to open a cached attachment.
+	// Defaults to xdg-open.
+	AttachmentViewer string
 }
 
 type Contact struct {
@@ -27,12 +47,25 @@ type Contact struct {
 	Name   string
 }
 
+// Key identifies the contact as a conversation party.
+func (c *Contact) Key() string {
+	return c.Number
+}
+
+func (c *Contact) DisplayName() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return c.Number
+}
+
 type Message struct {
 	Content     string
 	From        string
 	Timestamp   int64
 	IsDelivered bool
 	IsRead      bool
+	Attachments []Attachment
 }
 
 func (m *Message) String() string {
@@ -46,10 +79,12 @@ func (m *Message) String() string {
 }
 
 type Conversation struct {
-	Contact       *Contact
+	Party         Party
 	Messages      map[int64]*Message
 	MessageOrder  []int64
 	HasNewMessage bool
+	IsTyping      bool
+	TypingExpires time.Time
 }
 
 func (c *Conversation) String() string {
@@ -70,9 +105,9 @@ func (c *Conversation) AddMessage(message *Message) {
 	}
 }
 
-func NewConversation(contact *Contact) *Conversation {
+func NewConversation(party Party) *Conversation {
 	return &Conversation{
-		Contact:       contact,
+		Party:         party,
 		Messages:      make(map[int64]*Message),
 		MessageOrder:  make([]int64, 0),
 		HasNewMessage: false,
@@ -80,45 +115,115 @@ func NewConversation(contact *Contact) *Conversation {
 }
 
 type SignalAPI interface {
-	Send(string, string) error
-	Receive() error
+	Send(number, msg string, attachments []string) error
+	Receive(ctx context.Context) error
 	OnReceived(signal.ReceivedCallback)
 	OnReceipt(signal.ReceiptCallback)
+	SendTyping(number string, started bool) error
+	OnTyping(signal.TypingCallback)
+	SendReceipt(number string, timestamps []int64, read bool) error
+	SendGroup(groupID string, msg string) error
+}
+
+// pendingReceipt is a receipt for a message we haven't seen yet, kept
+// around until the message itself arrives.
+type pendingReceipt struct {
+	Timestamp   int64
+	IsDelivered bool
+	IsRead      bool
 }
 
 type Siggo struct {
-	config        *Config
-	contacts      map[string]*Contact
-	conversations map[*Contact]*Conversation
-	signal        SignalAPI
+	config            *Config
+	contacts          map[string]*Contact
+	groups            map[string]*Group
+	conversations     map[string]*Conversation
+	signal            SignalAPI
+	store             database.ConversationStore
+	pendingReceipts   map[string][]pendingReceipt
+	lastSendTimestamp int64
+
+	// mu guards contacts, groups, conversations and pendingReceipts.
+	// Once Run is wired up, the signal receiver's callbacks and the
+	// typing sweeper touch this state from separate goroutines, so
+	// every method that reads or writes it takes mu. The unexported
+	// helpers below (newConversation, newContact, newGroup,
+	// syncGroupRoster, resolveParty, applyPendingReceipts,
+	// receiptCandidates, findPendingMessage) assume the caller already
+	// holds mu.
+	mu sync.Mutex
 
 	NewInfo func(*Conversation)
 }
 
-// Send sends a message to a contact.
-func (s *Siggo) Send(msg string, contact *Contact) error {
+// Send sends a message, optionally with attachments, to a contact.
+func (s *Siggo) Send(msg string, contact *Contact, attachments []string) error {
+	s.mu.Lock()
 	// update for whoever wants to know
 	// ui might want to know immediately
-	conv, ok := s.conversations[contact]
+	conv, ok := s.conversations[contact.Key()]
 	if !ok {
 		conv = s.newConversation(contact)
 	}
 	message := &Message{
 		Content:     msg,
 		From:        s.config.UserName,
-		Timestamp:   0,
+		Timestamp:   s.nextSendTimestamp(),
 		IsDelivered: false,
 		IsRead:      false,
 	}
 	s.onSend(message, conv)
+	s.mu.Unlock()
+
+	if err := s.persistMessage(contact.Key(), message); err != nil {
+		log.Printf("error persisting sent message: %v", err)
+	}
 
 	// actually send the message
-	return s.signal.Send(contact.Number, msg)
+	return s.signal.Send(contact.Number, msg, attachments)
+}
+
+// SendGroup sends a message to a group.
+func (s *Siggo) SendGroup(msg string, g *Group) error {
+	s.mu.Lock()
+	conv, ok := s.conversations[g.Key()]
+	if !ok {
+		conv = s.newConversation(g)
+	}
+	message := &Message{
+		Content:     msg,
+		From:        s.config.UserName,
+		Timestamp:   s.nextSendTimestamp(),
+		IsDelivered: false,
+		IsRead:      false,
+	}
+	s.onSend(message, conv)
+	s.mu.Unlock()
+
+	if err := s.persistMessage(g.Key(), message); err != nil {
+		log.Printf("error persisting sent message: %v", err)
+	}
+
+	return s.signal.SendGroup(g.ID, msg)
 }
 
-func (s *Siggo) newConversation(contact *Contact) *Conversation {
-	conv := NewConversation(contact)
-	s.conversations[contact] = conv
+// nextSendTimestamp returns a millisecond timestamp for an outbound
+// message, guaranteed to be strictly greater than the one handed out
+// before it. Messages keyed by timestamp collide in both the in-memory
+// conversation and the store, so two Sends landing in the same clock
+// millisecond can't be allowed to share one.
+func (s *Siggo) nextSendTimestamp() int64 {
+	ts := time.Now().UnixMilli()
+	if ts <= s.lastSendTimestamp {
+		ts = s.lastSendTimestamp + 1
+	}
+	s.lastSendTimestamp = ts
+	return ts
+}
+
+func (s *Siggo) newConversation(party Party) *Conversation {
+	conv := NewConversation(party)
+	s.conversations[party.Key()] = conv
 	return conv
 }
 
@@ -130,14 +235,168 @@ func (s *Siggo) newContact(number string) *Contact {
 	return contact
 }
 
-// Receive
-func (s *Siggo) Receive() error {
-	return s.signal.Receive()
+func (s *Siggo) newGroup(id string) *Group {
+	group := NewGroup(id, "")
+	s.groups[id] = group
+	return group
+}
+
+// syncGroupRoster updates g's name and membership from a GroupInfo
+// block. The roster is populated from the full member list signal-cli
+// sent, not just whoever happened to send this particular message -
+// otherwise members who haven't spoken yet would never receive read
+// receipts.
+func (s *Siggo) syncGroupRoster(g *Group, info *signal.GroupInfo, sender *Contact) {
+	if info.Name != "" {
+		g.Name = info.Name
+	}
+	for _, number := range info.Members {
+		member, ok := s.contacts[number]
+		if !ok {
+			member = s.newContact(number)
+		}
+		g.AddMember(member)
+	}
+	g.AddMember(sender)
+}
+
+// resolveParty looks up (creating if necessary) the Party a persisted
+// conversation key refers to.
+func (s *Siggo) resolveParty(partyKey string) Party {
+	if id, ok := groupID(partyKey); ok {
+		if g, ok := s.groups[id]; ok {
+			return g
+		}
+		return s.newGroup(id)
+	}
+	if c, ok := s.contacts[partyKey]; ok {
+		return c
+	}
+	return s.newContact(partyKey)
+}
+
+// Run starts the signal receiver and siggo's background services (the
+// typing-expiry sweeper today, a sync worker eventually) under a
+// Supervisor, and blocks until ctx is cancelled. Cancelling ctx lets the
+// TUI shut down cleanly; a service that errors out is restarted with
+// backoff instead of taking the whole process down, so a signal-cli
+// daemon restart doesn't leak the UI.
+func (s *Siggo) Run(ctx context.Context) {
+	sv := NewSupervisor(
+		&signalReceiver{signal: s.signal},
+		&typingSweeper{siggo: s, interval: TypingExpiry},
+	)
+	sv.Run(ctx)
 }
 
 func (s *Siggo) onSend(message *Message, conv *Conversation) {}
 
+// NotifyTyping tells contact that the local user has started or stopped
+// composing a message. The UI calls this as the user types in the input
+// box.
+func (s *Siggo) NotifyTyping(contact *Contact, started bool) error {
+	return s.signal.SendTyping(contact.Number, started)
+}
+
+// onTyping updates a conversation's typing state and notifies the UI.
+func (s *Siggo) onTyping(number string, typing *signal.TypingMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.contacts[number]
+	if !ok {
+		c = s.newContact(number)
+	}
+	conv, ok := s.conversations[c.Key()]
+	if !ok {
+		conv = s.newConversation(c)
+	}
+	conv.IsTyping = typing.IsStarted()
+	conv.TypingExpires = time.Now().Add(TypingExpiry)
+	s.NewInfo(conv)
+	return nil
+}
+
+// expireTyping clears IsTyping on any conversation whose typing
+// notification is older than TypingExpiry, in case a "stopped"
+// notification never arrived.
+func (s *Siggo) expireTyping() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for _, conv := range s.conversations {
+		if conv.IsTyping && now.After(conv.TypingExpires) {
+			conv.IsTyping = false
+			s.NewInfo(conv)
+		}
+	}
+}
+
+// persistMessage saves the conversation (if new) and message to the
+// store, keyed by conversationKey (the party's Key: a contact's number
+// or a group's key).
+func (s *Siggo) persistMessage(conversationKey string, message *Message) error {
+	if err := s.store.SaveConversation(&database.Conversation{PartyKey: conversationKey}); err != nil {
+		return fmt.Errorf("saving conversation %s: %w", conversationKey, err)
+	}
+	dbMessage := &database.Message{
+		ConversationKey: conversationKey,
+		Content:         message.Content,
+		From:            message.From,
+		Timestamp:       message.Timestamp,
+		IsDelivered:     message.IsDelivered,
+		IsRead:          message.IsRead,
+		Attachments:     toDBAttachments(message.Attachments),
+	}
+	if err := s.store.SaveMessage(dbMessage); err != nil {
+		return fmt.Errorf("saving message %d in %s: %w", message.Timestamp, conversationKey, err)
+	}
+	return nil
+}
+
+// toDBAttachments converts a message's attachments to their persisted
+// representation.
+func toDBAttachments(attachments []Attachment) []database.Attachment {
+	if len(attachments) == 0 {
+		return nil
+	}
+	out := make([]database.Attachment, len(attachments))
+	for i, a := range attachments {
+		out[i] = database.Attachment{
+			ID:          a.ID,
+			ContentType: a.ContentType,
+			Filename:    a.Filename,
+			Size:        a.Size,
+			LocalPath:   a.LocalPath,
+		}
+	}
+	return out
+}
+
+// fromDBAttachments converts persisted attachments back into a message's
+// in-memory representation.
+func fromDBAttachments(attachments []database.Attachment) []Attachment {
+	if len(attachments) == 0 {
+		return nil
+	}
+	out := make([]Attachment, len(attachments))
+	for i, a := range attachments {
+		out[i] = Attachment{
+			ID:          a.ID,
+			ContentType: a.ContentType,
+			Filename:    a.Filename,
+			Size:        a.Size,
+			LocalPath:   a.LocalPath,
+		}
+	}
+	return out
+}
+
 func (s *Siggo) onReceived(msg *signal.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	// add new message to conversation
 	receiveMsg := msg.Envelope.DataMessage
 	contactNumber := msg.Envelope.Source
@@ -155,6 +414,9 @@ func (s *Siggo) onReceived(msg *signal.Message) error {
 		}
 		log.Printf("New contact: %v", c)
 		s.contacts[c.Number] = c
+		if err := s.store.SaveContact(&database.Contact{Number: c.Number}); err != nil {
+			log.Printf("error persisting new contact %s: %v", c.Number, err)
+		}
 	} else if c.Name == "" {
 		fromStr = contactNumber
 	} else {
@@ -167,17 +429,76 @@ func (s *Siggo) onReceived(msg *signal.Message) error {
 		IsDelivered: true,
 		IsRead:      false,
 	}
-	conv, ok := s.conversations[c]
+	for _, a := range receiveMsg.Attachments {
+		cached, err := s.cacheAttachment(&a)
+		if err != nil {
+			log.Printf("error caching attachment %s: %v", a.ID, err)
+			continue
+		}
+		message.Attachments = append(message.Attachments, cached)
+	}
+
+	// a groupInfo block means this is a group message - the conversation
+	// is keyed by the group, not by the sender
+	var party Party = c
+	if receiveMsg.GroupInfo != nil {
+		g, ok := s.groups[receiveMsg.GroupInfo.GroupID]
+		if !ok {
+			log.Printf("new group: %v", receiveMsg.GroupInfo.GroupID)
+			g = s.newGroup(receiveMsg.GroupInfo.GroupID)
+		}
+		s.syncGroupRoster(g, receiveMsg.GroupInfo, c)
+		party = g
+	}
+
+	conv, ok := s.conversations[party.Key()]
 	if !ok {
-		log.Printf("new conversation for contact: %v", c)
-		conv = s.newConversation(c)
+		log.Printf("new conversation for party: %v", party.Key())
+		conv = s.newConversation(party)
 	}
 	conv.AddMessage(message)
+	if err := s.persistMessage(party.Key(), message); err != nil {
+		log.Printf("error persisting received message: %v", err)
+	}
+	// receipts are always buffered under the sender's own number
+	// (onReceipt has no way to know which group a not-yet-seen message
+	// will land in), so replay against that key as well as the party's -
+	// for a group message those differ.
+	s.applyPendingReceipts(contactNumber, conv)
+	if party.Key() != contactNumber {
+		s.applyPendingReceipts(party.Key(), conv)
+	}
 	s.NewInfo(conv)
 	return nil
 }
 
+// applyPendingReceipts replays any receipts that arrived before the
+// message they refer to, now that conv has that message.
+func (s *Siggo) applyPendingReceipts(contactNumber string, conv *Conversation) {
+	pending := s.pendingReceipts[contactNumber]
+	if len(pending) == 0 {
+		return
+	}
+	var remaining []pendingReceipt
+	for _, p := range pending {
+		message, ok := conv.Messages[p.Timestamp]
+		if !ok {
+			remaining = append(remaining, p)
+			continue
+		}
+		message.IsDelivered = p.IsDelivered
+		message.IsRead = p.IsRead
+		if err := s.store.SetReceipt(conv.Party.Key(), p.Timestamp, p.IsDelivered, p.IsRead); err != nil {
+			log.Printf("error persisting replayed receipt for %d: %v", p.Timestamp, err)
+		}
+	}
+	s.pendingReceipts[contactNumber] = remaining
+}
+
 func (s *Siggo) onReceipt(msg *signal.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	receiptMsg := msg.Envelope.ReceiptMessage
 	//fmt.Printf("RECEIPT Received:\n")
 	//fmt.Printf("  From: %s\n", msg.Envelope.Source)
@@ -192,49 +513,224 @@ func (s *Siggo) onReceipt(msg *signal.Message) error {
 	if !ok {
 		c = s.newContact(contactNumber)
 	}
-	conv, ok := s.conversations[c]
-	if !ok {
-		conv = s.newConversation(c)
+
+	// a receipt for a group message arrives from one member, but it has
+	// to update the shared group conversation, not a DM with that
+	// member - so check the 1:1 conversation as well as every group c
+	// belongs to.
+	candidates := s.receiptCandidates(c)
+	if len(candidates) == 0 {
+		candidates = []*Conversation{s.newConversation(c)}
 	}
+
 	for _, ts := range receiptMsg.Timestamps {
-		message, ok := conv.Messages[ts]
-		if !ok {
-			// TODO: handle case where we get a read receipt for
-			// a message that we don't have
+		message, conv := s.findPendingMessage(candidates, ts)
+		if message == nil {
+			// the message hasn't arrived yet (receipts can race the
+			// message they refer to) - buffer it and replay once
+			// onReceived sees the message.
+			s.pendingReceipts[contactNumber] = append(s.pendingReceipts[contactNumber], pendingReceipt{
+				Timestamp:   ts,
+				IsDelivered: receiptMsg.IsDelivery,
+				IsRead:      receiptMsg.IsRead,
+			})
 			continue
 		}
 		message.IsDelivered = receiptMsg.IsDelivery
 		message.IsRead = receiptMsg.IsRead
+		if err := s.store.SetReceipt(conv.Party.Key(), ts, message.IsDelivered, message.IsRead); err != nil {
+			log.Printf("error persisting receipt for %d: %v", ts, err)
+		}
 	}
 	return nil
 }
 
-func (s *Siggo) Conversations() map[*Contact]*Conversation {
+// receiptCandidates returns every conversation a receipt from c could
+// belong to: the 1:1 conversation with c, plus every group c is a
+// member of.
+func (s *Siggo) receiptCandidates(c *Contact) []*Conversation {
+	var candidates []*Conversation
+	if conv, ok := s.conversations[c.Key()]; ok {
+		candidates = append(candidates, conv)
+	}
+	for _, g := range s.groups {
+		for _, m := range g.Members {
+			if m.Number != c.Number {
+				continue
+			}
+			if conv, ok := s.conversations[g.Key()]; ok {
+				candidates = append(candidates, conv)
+			}
+			break
+		}
+	}
+	return candidates
+}
+
+// findPendingMessage looks for timestamp ts across candidates, returning
+// the message and the conversation it was found in.
+func (s *Siggo) findPendingMessage(candidates []*Conversation, ts int64) (*Message, *Conversation) {
+	for _, conv := range candidates {
+		if message, ok := conv.Messages[ts]; ok {
+			return message, conv
+		}
+	}
+	return nil, nil
+}
+
+// MarkRead marks every inbound message in conv with Timestamp <= upTo as
+// read, both locally and by sending a read receipt to the party. It is
+// a no-op if there's nothing new to mark.
+func (s *Siggo) MarkRead(conv *Conversation, upTo int64) error {
+	s.mu.Lock()
+	var timestamps []int64
+	for _, ts := range conv.MessageOrder {
+		message := conv.Messages[ts]
+		if message.Timestamp > upTo || message.IsRead || message.From == s.config.UserName {
+			continue
+		}
+		message.IsRead = true
+		timestamps = append(timestamps, message.Timestamp)
+		if err := s.store.SetReceipt(conv.Party.Key(), message.Timestamp, message.IsDelivered, true); err != nil {
+			log.Printf("error persisting read receipt for %d: %v", message.Timestamp, err)
+		}
+	}
+	recipients := s.receiptRecipients(conv.Party)
+	s.mu.Unlock()
+
+	if len(timestamps) == 0 {
+		return nil
+	}
+	// Keep going even if one recipient fails - group members are sent
+	// receipts independently, and one bad send shouldn't swallow the
+	// rest of the group's.
+	var firstErr error
+	for _, number := range recipients {
+		if err := s.signal.SendReceipt(number, timestamps, true); err != nil {
+			log.Printf("error sending read receipt to %s: %v", number, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// receiptRecipients returns the phone numbers a read receipt for party
+// needs to be sent to: the contact itself, or every member of a group
+// other than the local user (GroupInfo's member list includes our own
+// number, and there's no one to send ourselves a receipt).
+func (s *Siggo) receiptRecipients(party Party) []string {
+	switch p := party.(type) {
+	case *Contact:
+		return []string{p.Number}
+	case *Group:
+		numbers := make([]string, 0, len(p.Members))
+		for _, m := range p.Members {
+			if m.Number == s.config.UserNumber {
+				continue
+			}
+			numbers = append(numbers, m.Number)
+		}
+		return numbers
+	default:
+		return nil
+	}
+}
+
+func (s *Siggo) Conversations() map[string]*Conversation {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	return s.conversations
 }
 
+func (s *Siggo) Groups() map[string]*Group {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.groups
+}
+
 func (s *Siggo) Contacts() map[string]*Contact {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	return s.contacts
 }
 
-// NewSiggo creates a new model
-func NewSiggo(sig SignalAPI, config *Config) *Siggo {
+// NewSiggo creates a new model, opening store (running migrations if
+// needed) and loading any persisted contacts, conversations and
+// messages.
+func NewSiggo(sig SignalAPI, config *Config, store database.ConversationStore) (*Siggo, error) {
+	if err := store.Open(); err != nil {
+		return nil, fmt.Errorf("opening store: %w", err)
+	}
 	contacts := GetContacts(config.UserNumber)
 	conversations := GetConversations(config.UserNumber, contacts)
 	s := &Siggo{
-		config:        config,
-		contacts:      contacts,
-		conversations: conversations,
-		signal:        sig,
+		config:          config,
+		contacts:        contacts,
+		groups:          make(map[string]*Group),
+		conversations:   conversations,
+		signal:          sig,
+		store:           store,
+		pendingReceipts: make(map[string][]pendingReceipt),
 
 		NewInfo: func(*Conversation) {}, // noop
 	}
+	if err := s.loadHistory(); err != nil {
+		return nil, fmt.Errorf("loading history: %w", err)
+	}
 	//sig.OnMessage(s.?)
 	//sig.OnSent(s.?)
 
 	sig.OnReceived(s.onReceived)
 	sig.OnReceipt(s.onReceipt)
-	return s
+	sig.OnTyping(s.onTyping)
+	return s, nil
+}
+
+// loadHistory populates contacts and conversations from the store,
+// giving siggo scrollback beyond the current session.
+func (s *Siggo) loadHistory() error {
+	storedContacts, err := s.store.Contacts()
+	if err != nil {
+		return fmt.Errorf("loading contacts: %w", err)
+	}
+	for _, sc := range storedContacts {
+		c, ok := s.contacts[sc.Number]
+		if !ok {
+			c = &Contact{Number: sc.Number}
+			s.contacts[sc.Number] = c
+		}
+		c.Name = sc.Name
+	}
+
+	storedConversations, err := s.store.Conversations()
+	if err != nil {
+		return fmt.Errorf("loading conversations: %w", err)
+	}
+	for _, sc := range storedConversations {
+		party := s.resolveParty(sc.PartyKey)
+		conv, ok := s.conversations[party.Key()]
+		if !ok {
+			conv = s.newConversation(party)
+		}
+		messages, err := s.store.Messages(sc.PartyKey)
+		if err != nil {
+			return fmt.Errorf("loading messages for %s: %w", sc.PartyKey, err)
+		}
+		for _, m := range messages {
+			conv.AddMessage(&Message{
+				Content:     m.Content,
+				From:        m.From,
+				Timestamp:   m.Timestamp,
+				IsDelivered: m.IsDelivered,
+				IsRead:      m.IsRead,
+				Attachments: fromDBAttachments(m.Attachments),
+			})
+		}
+		conv.HasNewMessage = false
+	}
+	return nil
 }
 
 // GetContacts reads the contact list from disk for a given user
@@ -249,12 +745,12 @@ func GetContacts(userNumber string) map[string]*Contact {
 
 // GetConversations reads conversations from disk for a given user
 // and contact list
-func GetConversations(userNumber string, contacts map[string]*Contact) map[*Contact]*Conversation {
-	conversations := make(map[*Contact]*Conversation)
+func GetConversations(userNumber string, contacts map[string]*Contact) map[string]*Conversation {
+	conversations := make(map[string]*Conversation)
 	for _, contact := range contacts {
 		fmt.Printf("Adding conversation for: %+v\n", contact)
 		conv := NewConversation(contact)
-		conversations[contact] = conv
+		conversations[contact.Key()] = conv
 	}
 	return conversations
 }
@@ -0,0 +1,339 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/derricw/siggo/database"
+	"github.com/derricw/siggo/signal"
+)
+
+// fakeStore is an in-memory database.ConversationStore used to exercise
+// Siggo's persistence calls without a real SQL backend.
+type fakeStore struct {
+	contacts      map[string]*database.Contact
+	conversations map[string]*database.Conversation
+	messages      map[string][]*database.Message
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{
+		contacts:      make(map[string]*database.Contact),
+		conversations: make(map[string]*database.Conversation),
+		messages:      make(map[string][]*database.Message),
+	}
+}
+
+func (f *fakeStore) Open() error  { return nil }
+func (f *fakeStore) Close() error { return nil }
+
+func (f *fakeStore) Contacts() ([]*database.Contact, error) {
+	var out []*database.Contact
+	for _, c := range f.contacts {
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+func (f *fakeStore) SaveContact(c *database.Contact) error {
+	f.contacts[c.Number] = c
+	return nil
+}
+
+func (f *fakeStore) Conversations() ([]*database.Conversation, error) {
+	var out []*database.Conversation
+	for _, c := range f.conversations {
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+func (f *fakeStore) SaveConversation(c *database.Conversation) error {
+	f.conversations[c.PartyKey] = c
+	return nil
+}
+
+func (f *fakeStore) Messages(conversationKey string) ([]*database.Message, error) {
+	return f.messages[conversationKey], nil
+}
+
+func (f *fakeStore) SaveMessage(m *database.Message) error {
+	for i, existing := range f.messages[m.ConversationKey] {
+		if existing.Timestamp == m.Timestamp {
+			f.messages[m.ConversationKey][i] = m
+			return nil
+		}
+	}
+	f.messages[m.ConversationKey] = append(f.messages[m.ConversationKey], m)
+	return nil
+}
+
+func (f *fakeStore) SetReceipt(conversationKey string, timestamp int64, isDelivered, isRead bool) error {
+	for _, m := range f.messages[conversationKey] {
+		if m.Timestamp == timestamp {
+			m.IsDelivered = isDelivered
+			m.IsRead = isRead
+		}
+	}
+	return nil
+}
+
+// noopSignal is a SignalAPI that does nothing, for tests that only care
+// about Siggo's local bookkeeping.
+type noopSignal struct{}
+
+func (noopSignal) Send(number, msg string, attachments []string) error           { return nil }
+func (noopSignal) Receive(ctx context.Context) error                            { return nil }
+func (noopSignal) OnReceived(signal.ReceivedCallback)                           {}
+func (noopSignal) OnReceipt(signal.ReceiptCallback)                             {}
+func (noopSignal) SendTyping(number string, started bool) error                 { return nil }
+func (noopSignal) OnTyping(signal.TypingCallback)                               {}
+func (noopSignal) SendReceipt(number string, timestamps []int64, read bool) error { return nil }
+func (noopSignal) SendGroup(groupID string, msg string) error                   { return nil }
+
+// recordingSignal is a SignalAPI that records every SendReceipt call and
+// lets a test fail specific recipients.
+type recordingSignal struct {
+	noopSignal
+	failFor map[string]bool
+	sent    []string
+}
+
+func (r *recordingSignal) SendReceipt(number string, timestamps []int64, read bool) error {
+	r.sent = append(r.sent, number)
+	if r.failFor[number] {
+		return fmt.Errorf("send receipt to %s: refused", number)
+	}
+	return nil
+}
+
+func TestSendPersistsDistinctMessages(t *testing.T) {
+	store := newFakeStore()
+	s := &Siggo{
+		config:          &Config{UserName: "me"},
+		contacts:        map[string]*Contact{},
+		groups:          map[string]*Group{},
+		conversations:   map[string]*Conversation{},
+		signal:          noopSignal{},
+		store:           store,
+		pendingReceipts: map[string][]pendingReceipt{},
+		NewInfo:         func(*Conversation) {},
+	}
+
+	contact := &Contact{Number: "+15555550100"}
+
+	if err := s.Send("hello", contact, nil); err != nil {
+		t.Fatalf("first Send: %v", err)
+	}
+	if err := s.Send("world", contact, nil); err != nil {
+		t.Fatalf("second Send: %v", err)
+	}
+
+	stored := store.messages[contact.Key()]
+	if len(stored) != 2 {
+		t.Fatalf("got %d persisted messages, want 2 (one got overwritten): %+v", len(stored), stored)
+	}
+}
+
+// TestGroupReceiptBufferedUnderSenderIsReplayed reproduces a receipt
+// that races ahead of the group message it refers to: onReceipt buffers
+// it under the sender's own number, since it has no way yet to know
+// which group the message will land in. Once the message arrives under
+// the group's party key, the buffered receipt must still be replayed.
+func TestGroupReceiptBufferedUnderSenderIsReplayed(t *testing.T) {
+	store := newFakeStore()
+	member := &Contact{Number: "+15555550100"}
+	group := NewGroup("group-1", "Friends")
+	group.AddMember(member)
+
+	s := &Siggo{
+		config:          &Config{UserName: "me"},
+		contacts:        map[string]*Contact{member.Number: member},
+		groups:          map[string]*Group{group.ID: group},
+		conversations:   map[string]*Conversation{},
+		signal:          noopSignal{},
+		store:           store,
+		pendingReceipts: map[string][]pendingReceipt{},
+		NewInfo:         func(*Conversation) {},
+	}
+
+	const ts = int64(42)
+
+	// a receipt for ts arrives before the message - onReceipt's path
+	s.pendingReceipts[member.Number] = append(s.pendingReceipts[member.Number], pendingReceipt{
+		Timestamp:   ts,
+		IsDelivered: true,
+		IsRead:      true,
+	})
+
+	// the group message itself now arrives - onReceived's path
+	conv := s.newConversation(group)
+	message := &Message{Content: "hi", From: member.Name, Timestamp: ts}
+	conv.AddMessage(message)
+	s.applyPendingReceipts(member.Number, conv)
+	if group.Key() != member.Number {
+		s.applyPendingReceipts(group.Key(), conv)
+	}
+
+	if !message.IsRead {
+		t.Fatalf("receipt buffered under sender number was never applied to the group message")
+	}
+	if len(s.pendingReceipts[member.Number]) != 0 {
+		t.Fatalf("pending receipt was not drained: %+v", s.pendingReceipts[member.Number])
+	}
+}
+
+// TestSyncGroupRosterUsesFullMemberList verifies the group roster is
+// populated from GroupInfo.Members, not just whoever sent this message.
+func TestSyncGroupRosterUsesFullMemberList(t *testing.T) {
+	s := &Siggo{
+		contacts: map[string]*Contact{},
+		groups:   map[string]*Group{},
+	}
+	sender := s.newContact("+15555550100")
+	g := NewGroup("group-1", "")
+
+	s.syncGroupRoster(g, &signal.GroupInfo{
+		Name:    "Friends",
+		Members: []string{"+15555550100", "+15555550101", "+15555550102"},
+	}, sender)
+
+	if g.Name != "Friends" {
+		t.Fatalf("got group name %q, want %q", g.Name, "Friends")
+	}
+	if len(g.Members) != 3 {
+		t.Fatalf("got %d members, want 3 (roster should include members who haven't spoken yet): %+v", len(g.Members), g.Members)
+	}
+}
+
+// TestConcurrentTypingAndExpireDoesNotRace drives onTyping and
+// expireTyping from separate goroutines, the way Run's signalReceiver
+// and typingSweeper services do, and checks it doesn't panic with
+// "concurrent map iteration and map write".
+func TestConcurrentTypingAndExpireDoesNotRace(t *testing.T) {
+	s := &Siggo{
+		config:          &Config{UserName: "me"},
+		contacts:        map[string]*Contact{},
+		groups:          map[string]*Group{},
+		conversations:   map[string]*Conversation{},
+		signal:          noopSignal{},
+		store:           newFakeStore(),
+		pendingReceipts: map[string][]pendingReceipt{},
+		NewInfo:         func(*Conversation) {},
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			s.onTyping("+15555550100", &signal.TypingMessage{Action: "STARTED"})
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			s.expireTyping()
+		}
+	}()
+	wg.Wait()
+}
+
+// TestPersistMessageRoundTripsAttachments verifies an attachment survives
+// a persistMessage/loadHistory round trip, so cached files are still
+// discoverable after a restart.
+func TestPersistMessageRoundTripsAttachments(t *testing.T) {
+	store := newFakeStore()
+	s := &Siggo{
+		config: &Config{UserNumber: "+15555550100"},
+		store:  store,
+	}
+	contact := &Contact{Number: "+15555550101"}
+
+	message := &Message{
+		Content:   "check this out",
+		From:      contact.Number,
+		Timestamp: 1,
+		Attachments: []Attachment{
+			{ID: "att-1", ContentType: "image/png", Filename: "photo.png", Size: 42, LocalPath: "/cache/att-1-photo.png"},
+		},
+	}
+	if err := s.persistMessage(contact.Key(), message); err != nil {
+		t.Fatalf("persistMessage: %v", err)
+	}
+
+	s2 := &Siggo{
+		config:        &Config{UserNumber: "+15555550100"},
+		contacts:      map[string]*Contact{},
+		groups:        map[string]*Group{},
+		conversations: map[string]*Conversation{},
+		store:         store,
+	}
+	if err := s2.loadHistory(); err != nil {
+		t.Fatalf("loadHistory: %v", err)
+	}
+
+	conv, ok := s2.conversations[contact.Key()]
+	if !ok {
+		t.Fatalf("conversation %s not loaded", contact.Key())
+	}
+	loaded, ok := conv.Messages[1]
+	if !ok {
+		t.Fatalf("message not loaded")
+	}
+	if len(loaded.Attachments) != 1 || loaded.Attachments[0].LocalPath != "/cache/att-1-photo.png" {
+		t.Fatalf("got attachments %+v, want the cached photo to round-trip", loaded.Attachments)
+	}
+}
+
+// TestReceiptRecipientsExcludesLocalUser verifies a group's own roster
+// entry for the local user never ends up as a receipt recipient - there's
+// no one to send ourselves a read receipt.
+func TestReceiptRecipientsExcludesLocalUser(t *testing.T) {
+	s := &Siggo{config: &Config{UserNumber: "+15555550100"}}
+	g := NewGroup("group-1", "Friends")
+	g.AddMember(&Contact{Number: "+15555550100"})
+	g.AddMember(&Contact{Number: "+15555550101"})
+
+	recipients := s.receiptRecipients(g)
+
+	if len(recipients) != 1 || recipients[0] != "+15555550101" {
+		t.Fatalf("got recipients %+v, want only the other member", recipients)
+	}
+}
+
+// TestMarkReadSendsToAllRecipientsDespiteError verifies one recipient's
+// SendReceipt error doesn't stop the rest of a group from getting theirs.
+func TestMarkReadSendsToAllRecipientsDespiteError(t *testing.T) {
+	store := newFakeStore()
+	member1 := &Contact{Number: "+15555550101"}
+	member2 := &Contact{Number: "+15555550102"}
+	group := NewGroup("group-1", "Friends")
+	group.AddMember(member1)
+	group.AddMember(member2)
+
+	sig := &recordingSignal{failFor: map[string]bool{member1.Number: true}}
+	s := &Siggo{
+		config:          &Config{UserName: "me"},
+		contacts:        map[string]*Contact{},
+		groups:          map[string]*Group{group.ID: group},
+		conversations:   map[string]*Conversation{},
+		signal:          sig,
+		store:           store,
+		pendingReceipts: map[string][]pendingReceipt{},
+		NewInfo:         func(*Conversation) {},
+	}
+
+	conv := s.newConversation(group)
+	conv.AddMessage(&Message{Content: "hi", From: member1.Name, Timestamp: 1})
+
+	err := s.MarkRead(conv, 1)
+	if err == nil {
+		t.Fatalf("MarkRead: want the first recipient's error surfaced, got nil")
+	}
+	if len(sig.sent) != 2 {
+		t.Fatalf("got %d SendReceipt calls, want 2 (one per member, regardless of the first failing): %+v", len(sig.sent), sig.sent)
+	}
+}
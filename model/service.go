@@ -0,0 +1,103 @@
+package model
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Service is a long-running background task supervised by a Supervisor.
+// Serve blocks until ctx is cancelled or it hits an error it can't
+// recover from.
+type Service interface {
+	Serve(ctx context.Context) error
+	String() string
+}
+
+// DefaultBackoff is how long a Supervisor waits before restarting a
+// Service that returned an error.
+const DefaultBackoff = 2 * time.Second
+
+// Supervisor runs a set of Services concurrently, restarting any that
+// exit with an error until ctx is cancelled.
+type Supervisor struct {
+	services []Service
+	backoff  time.Duration
+}
+
+// NewSupervisor creates a Supervisor for the given services.
+func NewSupervisor(services ...Service) *Supervisor {
+	return &Supervisor{services: services, backoff: DefaultBackoff}
+}
+
+// Run starts every service and blocks until ctx is cancelled and all of
+// them have returned.
+func (sv *Supervisor) Run(ctx context.Context) {
+	done := make(chan struct{})
+	for _, svc := range sv.services {
+		go func(svc Service) {
+			sv.runWithRestart(ctx, svc)
+			done <- struct{}{}
+		}(svc)
+	}
+	for range sv.services {
+		<-done
+	}
+}
+
+func (sv *Supervisor) runWithRestart(ctx context.Context, svc Service) {
+	for {
+		err := svc.Serve(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Printf("service %s exited: %v, restarting in %s", svc, err, sv.backoff)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(sv.backoff):
+		}
+	}
+}
+
+// signalReceiver runs SignalAPI.Receive under the supervisor so a
+// signal-cli daemon restart just gets retried with backoff instead of
+// killing the process.
+type signalReceiver struct {
+	signal SignalAPI
+}
+
+func (r *signalReceiver) String() string {
+	return "signal-receiver"
+}
+
+func (r *signalReceiver) Serve(ctx context.Context) error {
+	return r.signal.Receive(ctx)
+}
+
+// typingSweeper periodically clears IsTyping on conversations whose
+// typing notification has expired, in case a "stopped" notification
+// never arrives.
+type typingSweeper struct {
+	siggo    *Siggo
+	interval time.Duration
+}
+
+func (t *typingSweeper) String() string {
+	return "typing-expiry-sweeper"
+}
+
+func (t *typingSweeper) Serve(ctx context.Context) error {
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			t.siggo.expireTyping()
+		}
+	}
+}
@@ -0,0 +1,11 @@
+package signal
+
+// Attachment is the attachment metadata signal-cli reports inline on a
+// DataMessage. The actual file bytes live under signal-cli's own
+// attachments directory, named by ID.
+type Attachment struct {
+	ID          string `json:"id"`
+	ContentType string `json:"contentType"`
+	Filename    string `json:"filename"`
+	Size        int64  `json:"size"`
+}
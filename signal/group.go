@@ -0,0 +1,18 @@
+package signal
+
+// GroupInfo is the group metadata signal-cli attaches to a DataMessage
+// when it was sent to a group rather than directly to us.
+type GroupInfo struct {
+	GroupID string   `json:"groupId"`
+	Name    string   `json:"name"`
+	Members []string `json:"members"`
+}
+
+// SendGroup sends msg to every member of the group identified by
+// groupID.
+func (s *Signal) SendGroup(groupID string, msg string) error {
+	return s.sendJSONRPC("send", map[string]interface{}{
+		"groupId": groupID,
+		"message": msg,
+	})
+}
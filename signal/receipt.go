@@ -0,0 +1,16 @@
+package signal
+
+// SendReceipt tells signal-cli to send a receipt for the given message
+// timestamps to number: a "read" receipt when read is true, otherwise a
+// "viewed" receipt.
+func (s *Signal) SendReceipt(number string, timestamps []int64, read bool) error {
+	receiptType := "read"
+	if !read {
+		receiptType = "viewed"
+	}
+	return s.sendJSONRPC("sendReceipt", map[string]interface{}{
+		"recipient":        number,
+		"targetTimestamps": timestamps,
+		"type":             receiptType,
+	})
+}
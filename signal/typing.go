@@ -0,0 +1,39 @@
+package signal
+
+// TypingMessage is the typing/composing notification signal-cli reports
+// over its JSON-RPC interface when a peer starts or stops composing a
+// message.
+type TypingMessage struct {
+	Action    string `json:"action"` // "STARTED" or "STOPPED"
+	Timestamp int64  `json:"timestamp"`
+}
+
+// IsStarted reports whether this notification means the peer started
+// composing, as opposed to stopping.
+func (t *TypingMessage) IsStarted() bool {
+	return t.Action == "STARTED"
+}
+
+// TypingCallback is called whenever a typing notification is received
+// for a contact.
+type TypingCallback func(number string, typing *TypingMessage) error
+
+// SendTyping tells signal-cli to report our own typing state to number.
+// started is true when the user begins composing and false when they
+// stop or send.
+func (s *Signal) SendTyping(number string, started bool) error {
+	action := "stop"
+	if started {
+		action = "start"
+	}
+	return s.sendJSONRPC("sendTyping", map[string]interface{}{
+		"recipient": number,
+		"action":    action,
+	})
+}
+
+// OnTyping registers a callback invoked whenever a typing notification
+// is received.
+func (s *Signal) OnTyping(cb TypingCallback) {
+	s.typingCallback = cb
+}